@@ -1,21 +1,30 @@
 package cmd
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"regexp"
+	"strings"
+	"syscall"
 	"time"
 
 	yaml "gopkg.in/yaml.v2"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
 	"github.com/ovh/haproxy-exporter/core"
+	"github.com/ovh/haproxy-exporter/core/discovery"
 )
 
 var cfgFile string
@@ -72,15 +81,217 @@ func initConfig() {
 	}
 }
 
+// TLSConfig is the `tls:` block of a source entry.
+type TLSConfig struct {
+	CAFile             string `mapstructure:"ca_file" yaml:"ca_file"`
+	CertFile           string `mapstructure:"cert_file" yaml:"cert_file"`
+	KeyFile            string `mapstructure:"key_file" yaml:"key_file"`
+	ServerName         string `mapstructure:"server_name" yaml:"server_name"`
+	InsecureSkipVerify bool   `mapstructure:"insecure_skip_verify" yaml:"insecure_skip_verify"`
+}
+
 // Source defined a HAProxy stats source
 type Source struct {
 	Include string
 	URI     string
 	Labels  map[string]interface{}
+
+	// TLS configures the client used to fetch an http(s) URI. Ignored for
+	// file/unix sources.
+	TLS *TLSConfig
+	// Headers are set on every request fetching an http(s) URI, e.g.
+	// {"Authorization": "Bearer ..."} for a proxy fronting the stats page.
+	Headers map[string]string
+	// Command is the runtime-API command sent over a unix URI: "show stat"
+	// (default, CSV), "show stat typed" or "show stat json".
+	Command string
 }
 
 var sources []Source
 
+// acceptsPrometheusFormat reports whether an Accept header names the
+// Prometheus exposition format, the same "version=" parameter or
+// "application/openmetrics-text" media type client_golang's own scrape
+// clients send, as opposed to a generic or absent Accept that an existing
+// Sensision/Warp10 poller would send against /metrics.
+func acceptsPrometheusFormat(accept string) bool {
+	return strings.Contains(accept, "version=") || strings.Contains(accept, "openmetrics")
+}
+
+// buildTLS turns a source's `tls:` block into a *tls.Config, or nil if the
+// source didn't set one.
+func buildTLS(cfg *TLSConfig) (*tls.Config, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName:         cfg.ServerName,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+
+	if cfg.CAFile != "" {
+		pem, err := ioutil.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// DiscoveryConfig configures one entry under the `discovery:` YAML key.
+// Which fields apply depends on Type: "dns_sd", "consul_sd" or "file_sd".
+type DiscoveryConfig struct {
+	Type string
+
+	// dns_sd
+	Name string
+
+	// consul_sd
+	Address string
+	Service string
+	Token   string
+
+	// dns_sd / consul_sd: URI scheme/path built for each resolved target.
+	Scheme string
+	Path   string
+
+	// file_sd
+	Include string
+}
+
+// buildDiscoverers turns the `discovery:` config into Discoverers, skipping
+// and logging any entry with an unknown Type.
+func buildDiscoverers(cfgs []DiscoveryConfig) []discovery.Discoverer {
+	discoverers := make([]discovery.Discoverer, 0, len(cfgs))
+	for _, c := range cfgs {
+		switch c.Type {
+		case "dns_sd":
+			discoverers = append(discoverers, discovery.NewDNS(discovery.DNSConfig{
+				Name: c.Name, Scheme: c.Scheme, Path: c.Path,
+			}))
+		case "consul_sd":
+			discoverers = append(discoverers, discovery.NewConsul(discovery.ConsulConfig{
+				Address: c.Address, Service: c.Service, Token: c.Token, Scheme: c.Scheme, Path: c.Path,
+			}))
+		case "file_sd":
+			discoverers = append(discoverers, discovery.NewFile(discovery.FileConfig{Include: c.Include}))
+		default:
+			log.Errorf("discovery: unknown type %q", c.Type)
+		}
+	}
+	return discoverers
+}
+
+// runDiscovery fans every Discoverer's updates into a single reconcile loop:
+// each update replaces that discoverer's contribution to the desired set,
+// which is unioned across all discoverers and diffed against the exporters
+// currently registered from discovery. New URIs get a fresh core.Exporter
+// registered with the beamer and the Prometheus registry; URIs that dropped
+// out of every discoverer's set are unregistered, and their scrape (if any
+// is in flight) ends via the beamer's per-tick context.
+func runDiscovery(ctx context.Context, discoverers []discovery.Discoverer, b *core.Beamer, registry *prometheus.Registry, scrapeTimeout time.Duration, baseLabels map[string]string, metrics []string, relabelConfigs []core.RelabelConfig) {
+	type update struct {
+		idx     int
+		sources []discovery.Source
+	}
+
+	merged := make(chan update)
+	for idx, d := range discoverers {
+		idx, d := idx, d
+		ch := make(chan []discovery.Source)
+		go d.Run(ctx, ch)
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case srcs, ok := <-ch:
+					if !ok {
+						return
+					}
+					select {
+					case merged <- update{idx: idx, sources: srcs}:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	latest := make(map[int][]discovery.Source)
+	current := make(map[string]*core.Exporter)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case u := <-merged:
+			latest[u.idx] = u.sources
+
+			desired := make(map[string]map[string]string)
+			for _, srcs := range latest {
+				for _, s := range srcs {
+					labels := make(map[string]string, len(baseLabels)+len(s.Labels))
+					for k, v := range baseLabels {
+						labels[k] = v
+					}
+					for k, v := range s.Labels {
+						labels[k] = v
+					}
+					desired[s.URI] = labels
+				}
+			}
+
+			for uri, e := range current {
+				if _, ok := desired[uri]; ok {
+					continue
+				}
+				log.Infof("discovery: %s no longer discovered, removing", uri)
+				b.RemoveExporter(e)
+				registry.Unregister(e)
+				delete(current, uri)
+			}
+
+			for uri, labels := range desired {
+				if _, ok := current[uri]; ok {
+					continue
+				}
+				e, err := core.NewExporter(core.Config{
+					URI:     uri,
+					Timeout: scrapeTimeout,
+					Labels:  labels,
+					Metrics: metrics,
+					Relabel: relabelConfigs,
+				})
+				if err != nil {
+					log.Errorf("discovery: %s: %v", uri, err)
+					continue
+				}
+				log.Infof("discovery: %s discovered, adding", uri)
+				current[uri] = e
+				b.AddExporter(e)
+				registry.MustRegister(e)
+			}
+		}
+	}
+}
+
 // RootCmd launch the aggregator agent.
 var RootCmd = &cobra.Command{
 	Use:   "haproxy-exporter",
@@ -105,6 +316,13 @@ var RootCmd = &cobra.Command{
 			log.Fatal("No sources defined, dying")
 		}
 
+		// Load the relabel_configs pipeline, applied to every emitted series
+		// whichever exporter it came from.
+		var relabelConfigs []core.RelabelConfig
+		if err := viper.UnmarshalKey("relabel_configs", &relabelConfigs); err != nil {
+			log.Fatalf("Unable to read 'relabel_configs', %v", err)
+		}
+
 		// Build exporters
 		exporters := make([]*core.Exporter, len(sources))
 
@@ -119,10 +337,21 @@ var RootCmd = &cobra.Command{
 				labels[k] = fmt.Sprintf("%v", v)
 			}
 
-			exporter, err := core.NewExporter(s.URI,
-				time.Duration(viper.GetInt("scrapeTimeout"))*time.Millisecond,
-				labels,
-				viper.GetStringSlice("metrics"))
+			tlsConfig, err := buildTLS(s.TLS)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			exporter, err := core.NewExporter(core.Config{
+				URI:     s.URI,
+				Timeout: time.Duration(viper.GetInt("scrapeTimeout")) * time.Millisecond,
+				Labels:  labels,
+				Metrics: viper.GetStringSlice("metrics"),
+				Relabel: relabelConfigs,
+				TLS:     tlsConfig,
+				Headers: s.Headers,
+				Command: s.Command,
+			})
 			if err != nil {
 				log.Fatal(err)
 			}
@@ -130,25 +359,74 @@ var RootCmd = &cobra.Command{
 		}
 		log.Infof("Exporters started - %v", len(exporters))
 
+		// A SIGTERM cancels this context, which in turn cancels whatever
+		// scrape the beamer has in flight so the process exits promptly
+		// instead of waiting on a stuck stats socket.
+		ctx, cancel := context.WithCancel(context.Background())
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, syscall.SIGTERM)
+		go func() {
+			<-sigCh
+			log.Info("SIGTERM received, cancelling in-flight scrapes")
+			cancel()
+			os.Exit(0)
+		}()
+
 		// Start beamer
-		b := core.NewBeamer(exporters, viper.GetStringMapString("labels"))
+		scrapeTimeout := time.Duration(viper.GetInt("scrapeTimeout")) * time.Millisecond
+		b := core.NewBeamer(ctx, exporters, viper.GetStringMapString("labels"), scrapeTimeout)
 		log.Infof("Beamer started")
 
+		// Register the Prometheus collectors behind a dedicated registry so
+		// /metrics only ever exposes HAProxy stats, not Go runtime metrics.
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(b)
+		for _, e := range exporters {
+			registry.MustRegister(e)
+		}
+
+		// Dynamic source discovery: reconcile the beamer's exporter pool
+		// against whatever dns_sd/consul_sd/file_sd discoverers are
+		// configured, on top of the static sources above.
+		var discoveryCfgs []DiscoveryConfig
+		if err := viper.UnmarshalKey("discovery", &discoveryCfgs); err != nil {
+			log.Fatalf("Unable to read 'discovery', %v", err)
+		}
+		if discoverers := buildDiscoverers(discoveryCfgs); len(discoverers) > 0 {
+			go runDiscovery(ctx, discoverers, b, registry, scrapeTimeout, viper.GetStringMapString("labels"), viper.GetStringSlice("metrics"), relabelConfigs)
+			log.Infof("Discovery started - %v discoverer(s)", len(discoverers))
+		}
+
 		// Setup http
-		http.Handle("/metrics", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		prometheusHandler := promhttp.HandlerFor(registry, promhttp.HandlerOpts{ErrorLog: log.StandardLogger()})
+		sensisionHandler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 			w.Write(b.Metrics().Bytes())
 			for _, e := range exporters {
-				e.Lock()
 				w.Write(e.Metrics().Bytes())
-				e.Unlock()
 			}
-		}))
+		})
+		// /metrics content-negotiates: a Prometheus scraper's Accept header
+		// names its exposition format (text/plain;version=0.0.4 or
+		// OpenMetrics), so only that case gets the new Collector-based
+		// output. Anything else falls back to the Sensision GTS lines
+		// /metrics served before Prometheus support was added, so existing
+		// Warp10 pollers keep working unchanged; /sensision always serves
+		// that output regardless of Accept.
+		http.HandleFunc("/metrics", func(w http.ResponseWriter, req *http.Request) {
+			if acceptsPrometheusFormat(req.Header.Get("Accept")) {
+				prometheusHandler.ServeHTTP(w, req)
+				return
+			}
+			sensisionHandler.ServeHTTP(w, req)
+		})
+		http.Handle("/sensision", sensisionHandler)
 		http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 			w.Write([]byte(`<html>
 	             <head><title>Haproxy Exporter</title></head>
 	             <body>
 	             <h1>Haproxy Exporter</h1>
-	             <p><a href="/metrics">Metrics</a></p>
+	             <p><a href="/metrics">Metrics</a> (Prometheus)</p>
+	             <p><a href="/sensision">Sensision</a> (Warp10)</p>
 	             </body>
 	             </html>`))
 		})
@@ -171,9 +449,7 @@ var RootCmd = &cobra.Command{
 
 						file.Write(b.Metrics().Bytes())
 						for _, e := range exporters {
-							e.Lock()
 							file.Write(e.Metrics().Bytes())
-							e.Unlock()
 						}
 
 						file.Close()
@@ -2,47 +2,86 @@ package core
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"math"
+	"sort"
 	"sync"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/Sirupsen/logrus"
 	"github.com/spf13/viper"
 )
 
 // Beamer support HAProxy stats collection
 type Beamer struct {
-	mutex  sync.RWMutex
-	labels string
+	mutex     sync.RWMutex
+	labels    string
+	exporters []*Exporter
+	index     int
 
 	scrapeCounter  int64
 	scrapeSkiped   int64
 	scrapeFailures int64
+
+	labelNames      []string
+	labelValues     []string
+	scrapeDesc      *prometheus.Desc
+	scrapeSkipDesc  *prometheus.Desc
+	scrapeFailsDesc *prometheus.Desc
 }
 
-// NewBeamer create a beamer
-func NewBeamer(exporters []*Exporter, labels map[string]string) *Beamer {
-	delta := viper.GetInt("scanDuration") / len(exporters)
+// NewBeamer create a beamer. ctx is the beamer's parent context: cancelling
+// it (e.g. from a SIGTERM shutdown hook) cancels every in-flight per-tick
+// scrape instead of waiting for stuck sockets to time out on their own.
+//
+// The scan period is derived once from the initial exporter count and isn't
+// recomputed as exporters are later added or removed via AddExporter /
+// RemoveExporter (e.g. from dynamic source discovery).
+func NewBeamer(ctx context.Context, exporters []*Exporter, labels map[string]string, scrapeTimeout time.Duration) *Beamer {
+	n := len(exporters)
+	if n == 0 {
+		n = 1
+	}
+	delta := viper.GetInt("scanDuration") / n
 	p := math.Max(float64(delta), 1)
 	ticker := time.NewTicker(time.Duration(p) * time.Millisecond)
 	running := make(chan struct{}, viper.GetInt("maxConcurrent"))
-	i := 0
 
-	b := &Beamer{}
+	b := &Beamer{exporters: append([]*Exporter{}, exporters...)}
 
 	go func() {
 		for {
 			select {
+			case <-ctx.Done():
+				ticker.Stop()
+				return
 			case <-ticker.C:
 				select {
 				case running <- struct{}{}:
+					b.mutex.Lock()
+					if len(b.exporters) == 0 {
+						b.mutex.Unlock()
+						<-running
+						continue
+					}
+					if b.index >= len(b.exporters) {
+						b.index = 0
+					}
+					e := b.exporters[b.index]
+					b.index++
+					b.scrapeCounter++
+					b.mutex.Unlock()
+
 					go func() {
 						defer func() {
 							<-running
 						}()
-						e := exporters[i]
-						success := e.Scrape()
+
+						tickCtx, cancel := context.WithTimeout(ctx, scrapeTimeout)
+						defer cancel()
+						success := e.Scrape(tickCtx)
 
 						b.mutex.Lock()
 
@@ -52,12 +91,6 @@ func NewBeamer(exporters []*Exporter, labels map[string]string) *Beamer {
 						}
 						b.mutex.Unlock()
 					}()
-
-					b.scrapeCounter++
-					i++
-					if i >= len(exporters) {
-						i = 0
-					}
 				default:
 					b.mutex.Lock()
 					b.scrapeSkiped++
@@ -74,9 +107,80 @@ func NewBeamer(exporters []*Exporter, labels map[string]string) *Beamer {
 		b.labels += k + "=" + labels[k]
 	}
 
+	for k := range labels {
+		b.labelNames = append(b.labelNames, k)
+	}
+	sort.Strings(b.labelNames)
+	for _, k := range b.labelNames {
+		b.labelValues = append(b.labelValues, labels[k])
+	}
+
+	b.scrapeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "exporter", "scrape"),
+		"Total number of HAProxy scrapes performed.",
+		b.labelNames, nil,
+	)
+	b.scrapeSkipDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "exporter", "scrape_skipped"),
+		"Total number of scrapes skipped because maxConcurrent was reached.",
+		b.labelNames, nil,
+	)
+	b.scrapeFailsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "exporter", "scrape_failures"),
+		"Total number of scrapes that failed.",
+		b.labelNames, nil,
+	)
+
 	return b
 }
 
+// AddExporter registers a newly discovered exporter into the round-robin
+// rotation. The current index is left untouched so fleet growth doesn't
+// disturb exporters already in rotation.
+func (b *Beamer) AddExporter(e *Exporter) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.exporters = append(b.exporters, e)
+}
+
+// RemoveExporter drops an exporter that's no longer discovered, clamping the
+// round-robin index if its removal shifted it out of range.
+func (b *Beamer) RemoveExporter(e *Exporter) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for i, existing := range b.exporters {
+		if existing != e {
+			continue
+		}
+		b.exporters = append(b.exporters[:i], b.exporters[i+1:]...)
+		if b.index > i {
+			b.index--
+		}
+		break
+	}
+	if b.index >= len(b.exporters) {
+		b.index = 0
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (b *Beamer) Describe(ch chan<- *prometheus.Desc) {
+	ch <- b.scrapeDesc
+	ch <- b.scrapeSkipDesc
+	ch <- b.scrapeFailsDesc
+}
+
+// Collect implements prometheus.Collector.
+func (b *Beamer) Collect(ch chan<- prometheus.Metric) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	ch <- prometheus.MustNewConstMetric(b.scrapeDesc, prometheus.CounterValue, float64(b.scrapeCounter), b.labelValues...)
+	ch <- prometheus.MustNewConstMetric(b.scrapeSkipDesc, prometheus.CounterValue, float64(b.scrapeSkiped), b.labelValues...)
+	ch <- prometheus.MustNewConstMetric(b.scrapeFailsDesc, prometheus.CounterValue, float64(b.scrapeFailures), b.labelValues...)
+}
+
 // Metrics delivers beamer stats as warp10 metrics.
 func (b *Beamer) Metrics() *bytes.Buffer {
 	b.mutex.RLock()
@@ -0,0 +1,141 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// ConsulConfig configures a consul_sd Discoverer.
+type ConsulConfig struct {
+	// Address is the Consul HTTP API base, e.g. "http://127.0.0.1:8500".
+	Address string
+	// Service is the service name to watch via /v1/health/service.
+	Service string
+	// Token is an optional ACL token sent as X-Consul-Token.
+	Token string
+	// Scheme and Path build the URI for each healthy instance:
+	// scheme://address:port/path. Scheme defaults to "http".
+	Scheme string
+	Path   string
+	// WaitTime bounds each blocking query. Defaults to 5m.
+	WaitTime time.Duration
+}
+
+type consulHealthEntry struct {
+	Node struct {
+		Node string
+	}
+	Service struct {
+		Address string
+		Port    int
+		Tags    []string
+	}
+}
+
+type consulDiscoverer struct {
+	cfg    ConsulConfig
+	client *http.Client
+}
+
+// NewConsul returns a Discoverer that watches a Consul service via blocking
+// queries against /v1/health/service and publishes one Source per healthy
+// instance, labelled with its node name and service tags.
+func NewConsul(cfg ConsulConfig) Discoverer {
+	if cfg.Scheme == "" {
+		cfg.Scheme = "http"
+	}
+	if cfg.WaitTime <= 0 {
+		cfg.WaitTime = 5 * time.Minute
+	}
+	return &consulDiscoverer{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.WaitTime + 30*time.Second},
+	}
+}
+
+func (d *consulDiscoverer) Run(ctx context.Context, ch chan<- []Source) {
+	var index string
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		entries, newIndex, err := d.watch(ctx, index)
+		if err != nil {
+			log.Errorf("consul_sd: watch %s failed: %v", d.cfg.Service, err)
+			select {
+			case <-time.After(5 * time.Second):
+			case <-ctx.Done():
+				return
+			}
+			continue
+		}
+		index = newIndex
+
+		sources := make([]Source, 0, len(entries))
+		for _, e := range entries {
+			labels := map[string]string{"__meta_consul_node": e.Node.Node}
+			for i, tag := range e.Service.Tags {
+				labels[fmt.Sprintf("__meta_consul_tag_%d", i)] = tag
+			}
+			sources = append(sources, Source{
+				URI:    fmt.Sprintf("%s://%s:%d%s", d.cfg.Scheme, e.Service.Address, e.Service.Port, d.cfg.Path),
+				Labels: labels,
+			})
+		}
+
+		select {
+		case ch <- sources:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// watch issues a single blocking /v1/health/service query, returning the
+// healthy instances and the index to pass to the next call.
+func (d *consulDiscoverer) watch(ctx context.Context, index string) ([]consulHealthEntry, string, error) {
+	u := fmt.Sprintf("%s/v1/health/service/%s?passing=1&wait=%s&index=%s",
+		d.cfg.Address, url.PathEscape(d.cfg.Service), d.cfg.WaitTime, url.QueryEscape(index))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	if d.cfg.Token != "" {
+		req.Header.Set("X-Consul-Token", d.cfg.Token)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("consul returned HTTP %d", resp.StatusCode)
+	}
+
+	var entries []consulHealthEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, "", err
+	}
+
+	// Consul's blocking-query contract: only trust a numeric index, and
+	// fall back to the previous one otherwise so we don't spin.
+	newIndex := resp.Header.Get("X-Consul-Index")
+	if _, err := strconv.ParseUint(newIndex, 10, 64); err != nil {
+		newIndex = index
+	}
+
+	return entries, newIndex, nil
+}
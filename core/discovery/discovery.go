@@ -0,0 +1,24 @@
+// Package discovery implements pluggable dynamic source discovery for the
+// HAProxy exporter, modeled on Prometheus's service discovery: a Discoverer
+// watches some external source of truth and republishes the full, current
+// set of targets whenever it changes.
+package discovery
+
+import "context"
+
+// Source is a discovered HAProxy stats endpoint: a URI core.NewExporter can
+// dial, plus whatever meta labels the discovery mechanism knows about it
+// (e.g. __meta_dns_name, or a Consul node name).
+type Source struct {
+	URI    string
+	Labels map[string]string
+}
+
+// Discoverer watches an external source of truth and publishes the full,
+// current set of Sources on ch every time it changes, replacing whatever it
+// last published. Run blocks until ctx is cancelled, except that an
+// implementation which cannot start (e.g. file_sd with an invalid pattern or
+// an unwatchable directory) logs the error and returns without publishing.
+type Discoverer interface {
+	Run(ctx context.Context, ch chan<- []Source)
+}
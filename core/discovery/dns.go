@@ -0,0 +1,81 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// DNSConfig configures a dns_sd Discoverer.
+type DNSConfig struct {
+	// Name is the fully qualified SRV record to resolve, e.g.
+	// "_stats._tcp.haproxy.service.consul".
+	Name string
+	// Scheme and Path build the URI for each resolved target:
+	// scheme://target:port/path. Scheme defaults to "http".
+	Scheme string
+	Path   string
+	// RefreshInterval sets how often Name is re-resolved. Defaults to 30s.
+	RefreshInterval time.Duration
+}
+
+type dnsDiscoverer struct {
+	cfg DNSConfig
+}
+
+// NewDNS returns a Discoverer that periodically resolves an SRV record and
+// publishes one Source per record, labelled with __meta_dns_name.
+func NewDNS(cfg DNSConfig) Discoverer {
+	if cfg.Scheme == "" {
+		cfg.Scheme = "http"
+	}
+	if cfg.RefreshInterval <= 0 {
+		cfg.RefreshInterval = 30 * time.Second
+	}
+	return &dnsDiscoverer{cfg: cfg}
+}
+
+func (d *dnsDiscoverer) Run(ctx context.Context, ch chan<- []Source) {
+	ticker := time.NewTicker(d.cfg.RefreshInterval)
+	defer ticker.Stop()
+
+	d.resolve(ctx, ch)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.resolve(ctx, ch)
+		}
+	}
+}
+
+func (d *dnsDiscoverer) resolve(ctx context.Context, ch chan<- []Source) {
+	// service/proto are left empty because Name is already a fully
+	// qualified SRV record.
+	_, records, err := net.DefaultResolver.LookupSRV(ctx, "", "", d.cfg.Name)
+	if err != nil {
+		log.Errorf("dns_sd: lookup %s failed: %v", d.cfg.Name, err)
+		return
+	}
+
+	sources := make([]Source, 0, len(records))
+	for _, rec := range records {
+		target := strings.TrimSuffix(rec.Target, ".")
+		sources = append(sources, Source{
+			URI: fmt.Sprintf("%s://%s:%d%s", d.cfg.Scheme, target, rec.Port, d.cfg.Path),
+			Labels: map[string]string{
+				"__meta_dns_name": d.cfg.Name,
+			},
+		})
+	}
+
+	select {
+	case ch <- sources:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,125 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+
+	"github.com/fsnotify/fsnotify"
+	yaml "gopkg.in/yaml.v2"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// fileSource reads the uri and labels keys of the on-disk YAML shape of a
+// static source entry, the same shape cmd's Include files already use. Other
+// keys of that shape (include, tls, headers, command) are ignored.
+type fileSource struct {
+	URI    string
+	Labels map[string]interface{}
+}
+
+// FileConfig configures a file_sd Discoverer.
+type FileConfig struct {
+	// Include is a directory plus a basename pattern: the directory part is
+	// watched, and files whose basename matches the pattern part, read as a
+	// regular expression (not a glob), are parsed as YAML arrays of source
+	// entries.
+	Include string
+}
+
+type fileDiscoverer struct {
+	cfg FileConfig
+}
+
+// NewFile returns a Discoverer that loads the YAML files matched by
+// cfg.Include and republishes their Sources whenever fsnotify reports a
+// change to that directory, instead of requiring a process restart.
+func NewFile(cfg FileConfig) Discoverer {
+	return &fileDiscoverer{cfg: cfg}
+}
+
+func (d *fileDiscoverer) Run(ctx context.Context, ch chan<- []Source) {
+	dir := filepath.Dir(d.cfg.Include)
+	pattern, err := regexp.Compile(filepath.Base(d.cfg.Include))
+	if err != nil {
+		log.Errorf("file_sd: bad pattern %s: %v", d.cfg.Include, err)
+		return
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Errorf("file_sd: %v", err)
+		return
+	}
+	defer watcher.Close()
+
+	if err := watcher.Add(dir); err != nil {
+		log.Errorf("file_sd: watch %s: %v", dir, err)
+		return
+	}
+
+	d.reload(ctx, dir, pattern, ch)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if !pattern.MatchString(filepath.Base(event.Name)) {
+				continue
+			}
+			d.reload(ctx, dir, pattern, ch)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Errorf("file_sd: %v", err)
+		}
+	}
+}
+
+func (d *fileDiscoverer) reload(ctx context.Context, dir string, pattern *regexp.Regexp, ch chan<- []Source) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		log.Errorf("file_sd: read %s: %v", dir, err)
+		return
+	}
+
+	var sources []Source
+	for _, entry := range entries {
+		if entry.IsDir() || !pattern.MatchString(entry.Name()) {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			log.Errorf("file_sd: read %s: %v", path, err)
+			continue
+		}
+
+		var fileSources []fileSource
+		if err := yaml.Unmarshal(data, &fileSources); err != nil {
+			log.Errorf("file_sd: %s should contain an array of source: %v", path, err)
+			continue
+		}
+
+		for _, s := range fileSources {
+			labels := make(map[string]string, len(s.Labels))
+			for k, v := range s.Labels {
+				labels[k] = fmt.Sprintf("%v", v)
+			}
+			sources = append(sources, Source{URI: s.URI, Labels: labels})
+		}
+	}
+
+	select {
+	case ch <- sources:
+	case <-ctx.Done():
+	}
+}
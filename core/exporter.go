@@ -1,8 +1,12 @@
 package core
 
 import (
+	"bufio"
 	"bytes"
+	"context"
+	"crypto/tls"
 	// "encoding/csv"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/gwenn/yacr"
@@ -10,48 +14,237 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/Sirupsen/logrus"
 )
 
 const (
 	rowLength = 62
+
+	namespace = "haproxy"
+
+	// defaultCommand is the runtime-API command sent by fetchUnix, and the
+	// parser Scrape falls back to, when a source doesn't set Command.
+	defaultCommand = "show stat"
 )
 
+// counterFields lists the stat fields that are monotonically increasing
+// counters; everything else in Exporter.metrics is exposed as a gauge.
+var counterFields = map[string]bool{
+	"bin":        true,
+	"bout":       true,
+	"stot":       true,
+	"dreq":       true,
+	"dresp":      true,
+	"ereq":       true,
+	"econ":       true,
+	"eresp":      true,
+	"wretr":      true,
+	"wredis":     true,
+	"chkfail":    true,
+	"chkdown":    true,
+	"hrsp_1xx":   true,
+	"hrsp_2xx":   true,
+	"hrsp_3xx":   true,
+	"hrsp_4xx":   true,
+	"hrsp_5xx":   true,
+	"hrsp_other": true,
+	"req_tot":    true,
+	"cli_abrt":   true,
+	"srv_abrt":   true,
+	"comp_in":    true,
+	"comp_out":   true,
+	"comp_byp":   true,
+	"comp_rsp":   true,
+}
+
+// statRow is one emitted series after relabeling: a metric name, its final
+// label set and value, kept around so it can be replayed to a Prometheus
+// Collect call without re-running the relabel pipeline.
+type statRow struct {
+	name   string
+	labels map[string]string
+	value  string
+}
+
+// deadlineTimer is a cancellable, re-armable scrape deadline, modeled after
+// netstack's deadlineTimer: reaching the deadline closes a cancellation
+// channel instead of erroring out a specific call, so any number of readers
+// can observe it. Re-arming (SetScrapeDeadline) swaps in a fresh channel and
+// stops the previous timer, so it never races a fetch that is already
+// watching the previous generation's channel.
+type deadlineTimer struct {
+	mu            sync.Mutex
+	deadline      time.Time
+	timer         *time.Timer
+	readCancelCh  chan struct{}
+	writeCancelCh chan struct{}
+}
+
+func newDeadlineTimer() *deadlineTimer {
+	return &deadlineTimer{
+		readCancelCh:  make(chan struct{}),
+		writeCancelCh: make(chan struct{}),
+	}
+}
+
+// Deadline returns the currently armed deadline, or the zero Time if none is set.
+func (d *deadlineTimer) Deadline() time.Time {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.deadline
+}
+
+func (d *deadlineTimer) readCancel() <-chan struct{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.readCancelCh
+}
+
+// SetScrapeDeadline arms (or, with a zero Time, disarms) the deadline for the
+// current or next scrape. Calling it again extends or shrinks the deadline
+// without disturbing a fetch already in flight against the previous one.
+func (d *deadlineTimer) SetScrapeDeadline(t time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+
+	d.deadline = t
+	d.readCancelCh = make(chan struct{})
+	d.writeCancelCh = make(chan struct{})
+
+	if t.IsZero() {
+		return
+	}
+
+	readCh, writeCh := d.readCancelCh, d.writeCancelCh
+	dur := time.Until(t)
+	if dur <= 0 {
+		close(readCh)
+		close(writeCh)
+		return
+	}
+
+	d.timer = time.AfterFunc(dur, func() {
+		close(readCh)
+		close(writeCh)
+	})
+}
+
+// deadlineConn re-applies the exporter's current scrape deadline before every
+// Read, so a SetScrapeDeadline call made mid-fetch takes effect on the very
+// next read instead of only at dial time.
+type deadlineConn struct {
+	net.Conn
+	deadline *deadlineTimer
+}
+
+func (c *deadlineConn) Read(p []byte) (int, error) {
+	select {
+	case <-c.deadline.readCancel():
+		return 0, context.DeadlineExceeded
+	default:
+	}
+
+	if d := c.deadline.Deadline(); !d.IsZero() {
+		if err := c.Conn.SetReadDeadline(d); err != nil {
+			return 0, err
+		}
+	}
+
+	return c.Conn.Read(p)
+}
+
 // Exporter collects HAProxy stats from the given URI and exports them as
 // warp10 metrics package.
 type Exporter struct {
 	URI   string
 	mutex sync.RWMutex
-	fetch func() (io.ReadCloser, error)
+	fetch func(ctx context.Context) (io.ReadCloser, error)
+
+	deadline *deadlineTimer
 
-	metrics   map[int]string
-	sensision bytes.Buffer
-	labels    string
+	// command is the runtime-API command fetchUnix sends, and which of
+	// scrapeCSV/parseTyped/parseJSON Scrape parses the response with.
+	command string
+
+	metrics       map[int]string
+	trackedFields map[string]bool
+	sensision     bytes.Buffer
+	rows          []statRow
+
+	userLabels map[string]string
+	relabel    []RelabelConfig
 }
 
-// NewExporter returns an initialized Exporter.
-func NewExporter(uri string, timeout time.Duration, labels map[string]string, metrics []string) (*Exporter, error) {
-	u, err := url.Parse(uri)
+// Config holds everything NewExporter needs to build an Exporter. It grew
+// out of NewExporter's argument list once TLS and per-source headers joined
+// URI/Timeout/Labels/Metrics/Relabel.
+type Config struct {
+	URI     string
+	Timeout time.Duration
+	Labels  map[string]string
+	Metrics []string
+	Relabel []RelabelConfig
+
+	// TLS configures fetchHTTP's client transport. Nil keeps the default
+	// transport: https URIs use the system roots and no client certificate.
+	TLS *tls.Config
+	// Headers are set on every fetchHTTP request, e.g. to carry an
+	// "Authorization" header through a proxy fronting the stats endpoint.
+	Headers map[string]string
+	// Command is the runtime-API command: "show stat" (default, CSV),
+	// "show stat typed" or "show stat json". Empty means the default.
+	Command string
+}
+
+// SetScrapeDeadline arms (or, with a zero Time, disarms) the deadline for the
+// exporter's current or next scrape. It may be called concurrently with
+// Scrape to extend or shrink an in-flight fetch's deadline.
+func (e *Exporter) SetScrapeDeadline(t time.Time) {
+	e.deadline.SetScrapeDeadline(t)
+}
+
+// NewExporter returns an initialized Exporter. cfg.Relabel is an ordered
+// relabel_configs pipeline, evaluated once per emitted series; pass nil for
+// no relabeling.
+func NewExporter(cfg Config) (*Exporter, error) {
+	u, err := url.Parse(cfg.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	relabel, err := compileRelabelConfigs(cfg.Relabel)
 	if err != nil {
 		return nil, err
 	}
 
-	var fetch func() (io.ReadCloser, error)
 	switch u.Scheme {
-	case "http", "https", "file":
-		fetch = fetchHTTP(uri, timeout)
-	case "unix":
-		fetch = fetchUnix(u, timeout)
+	case "http", "https", "file", "unix":
 	default:
 		return nil, fmt.Errorf("unsupported scheme: %q", u.Scheme)
 	}
 
+	command := cfg.Command
+	if command == "" {
+		command = defaultCommand
+	}
+
 	e := &Exporter{
-		URI:   uri,
-		fetch: fetch,
+		URI:      cfg.URI,
+		deadline: newDeadlineTimer(),
+		relabel:  relabel,
+		command:  command,
 		metrics: map[int]string{
 			// pxname
 			// svname
@@ -119,12 +312,19 @@ func NewExporter(uri string, timeout time.Duration, labels map[string]string, me
 		},
 	}
 
+	switch u.Scheme {
+	case "http", "https", "file":
+		e.fetch = fetchHTTP(cfg.URI, cfg.Timeout, cfg.TLS, cfg.Headers)
+	case "unix":
+		e.fetch = fetchUnix(u, e, cfg.Timeout)
+	}
+
 	// filter
-	if len(metrics) > 0 {
+	if len(cfg.Metrics) > 0 {
 		for i := range e.metrics {
 			found := false
-			for m := range metrics {
-				if e.metrics[i] == metrics[m] {
+			for m := range cfg.Metrics {
+				if e.metrics[i] == cfg.Metrics[m] {
 
 					found = true
 					break
@@ -137,10 +337,13 @@ func NewExporter(uri string, timeout time.Duration, labels map[string]string, me
 		}
 	}
 
-	for k := range labels {
-		e.labels += k + "=" + labels[k] + ","
+	e.trackedFields = make(map[string]bool, len(e.metrics))
+	for _, name := range e.metrics {
+		e.trackedFields[name] = true
 	}
 
+	e.userLabels = cfg.Labels
+
 	return e, nil
 }
 
@@ -152,13 +355,104 @@ func (e *Exporter) Metrics() *bytes.Buffer {
 	return bytes.NewBuffer(e.sensision.Bytes())
 }
 
-func fetchHTTP(uri string, timeout time.Duration) func() (io.ReadCloser, error) {
+// Describe implements prometheus.Collector. It intentionally sends nothing:
+// relabel_configs (replace/labeldrop/labelkeep/hashmod) can change a series'
+// label dimensions at runtime, so this is an "unchecked" Collector and
+// Collect builds each series' Desc on the fly instead.
+func (e *Exporter) Describe(ch chan<- *prometheus.Desc) {}
+
+// Collect implements prometheus.Collector, replaying the last scrape's
+// already-relabeled rows as GaugeVec/CounterVec style samples.
+func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
+	e.mutex.RLock()
+	rows := e.rows
+	e.mutex.RUnlock()
+
+	// A relabel rule (replace/labeldrop/hashmod) can touch only a subset of
+	// a family's rows, so two rows sharing row.name can end up with
+	// different label *names*, not just different values. registry.Gather
+	// rejects that as "inconsistent label dimensions" for the whole family,
+	// so build each family's Desc over the union of its rows' label names,
+	// the way Prometheus's own relabeling does, and fill absent ones with
+	// "" rather than per-row key sets.
+	familyLabels := make(map[string][]string)
+	seenLabel := make(map[string]map[string]bool)
+	for _, row := range rows {
+		if seenLabel[row.name] == nil {
+			seenLabel[row.name] = make(map[string]bool, len(row.labels))
+		}
+		for name := range row.labels {
+			if !seenLabel[row.name][name] {
+				seenLabel[row.name][name] = true
+				familyLabels[row.name] = append(familyLabels[row.name], name)
+			}
+		}
+	}
+
+	descs := make(map[string]*prometheus.Desc, len(familyLabels))
+	for name, labelNames := range familyLabels {
+		sort.Strings(labelNames)
+		descs[name] = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", name),
+			fmt.Sprintf("HAProxy %s stat, as reported by the stats socket.", name),
+			labelNames, nil,
+		)
+	}
+
+	// A relabel rule that drops a distinguishing label (e.g. labeldrop of
+	// svname) can collapse two rows to the same name+labels. Prometheus's
+	// registry rejects a Gather containing duplicate series for the whole
+	// /metrics response, not just the offending one, so dedupe here instead
+	// of letting a single bad rule blank the endpoint.
+	seen := make(map[string]bool, len(rows))
+
+	for _, row := range rows {
+		value, err := strconv.ParseFloat(row.value, 64)
+		if err != nil {
+			log.Debugf("skipping non-numeric %s=%q for %v", row.name, row.value, row.labels)
+			continue
+		}
+
+		labelNames := familyLabels[row.name]
+		values := make([]string, len(labelNames))
+		for i, name := range labelNames {
+			values[i] = row.labels[name]
+		}
+
+		key := row.name + "\xff" + strings.Join(values, "\xff")
+		if seen[key] {
+			log.Warnf("dropping duplicate series %s%v: a relabel rule collapsed distinct rows to the same labels", row.name, row.labels)
+			continue
+		}
+		seen[key] = true
+
+		valueType := prometheus.GaugeValue
+		if counterFields[row.name] {
+			valueType = prometheus.CounterValue
+		}
+
+		ch <- prometheus.MustNewConstMetric(descs[row.name], valueType, value, values...)
+	}
+}
+
+func fetchHTTP(uri string, timeout time.Duration, tlsConfig *tls.Config, headers map[string]string) func(ctx context.Context) (io.ReadCloser, error) {
 	client := http.Client{
 		Timeout: timeout,
 	}
+	if tlsConfig != nil {
+		client.Transport = &http.Transport{TLSClientConfig: tlsConfig}
+	}
+
+	return func(ctx context.Context) (io.ReadCloser, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
 
-	return func() (io.ReadCloser, error) {
-		resp, err := client.Get(uri)
+		resp, err := client.Do(req)
 		if err != nil {
 			return nil, err
 		}
@@ -171,30 +465,59 @@ func fetchHTTP(uri string, timeout time.Duration) func() (io.ReadCloser, error)
 	}
 }
 
-func fetchUnix(u *url.URL, timeout time.Duration) func() (io.ReadCloser, error) {
-	return func() (io.ReadCloser, error) {
-		f, err := net.DialTimeout("unix", u.Path, timeout)
+func fetchUnix(u *url.URL, e *Exporter, timeout time.Duration) func(ctx context.Context) (io.ReadCloser, error) {
+	return func(ctx context.Context) (io.ReadCloser, error) {
+		dialer := net.Dialer{Timeout: timeout}
+		conn, err := dialer.DialContext(ctx, "unix", u.Path)
 		if err != nil {
 			return nil, err
 		}
-		if err := f.SetDeadline(time.Now().Add(timeout)); err != nil {
-			f.Close()
+
+		if e.deadline.Deadline().IsZero() {
+			e.SetScrapeDeadline(time.Now().Add(timeout))
+		}
+		if err := conn.SetDeadline(e.deadline.Deadline()); err != nil {
+			conn.Close()
 			return nil, err
 		}
-		cmd := "show stat\n"
-		n, err := io.WriteString(f, cmd)
+
+		dc := &deadlineConn{Conn: conn, deadline: e.deadline}
+
+		cmd := e.command + "\n"
+		n, err := io.WriteString(dc, cmd)
 		if err != nil {
-			f.Close()
+			dc.Close()
 			return nil, err
 		}
 		if n != len(cmd) {
-			f.Close()
+			dc.Close()
 			return nil, errors.New("write error")
 		}
-		return f, nil
+		return dc, nil
 	}
 }
 
+// formatSensisionLabels renders a label set as Sensision's "k=v,k=v" label
+// list, sorted for deterministic output.
+func formatSensisionLabels(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for i, name := range names {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString(name)
+		b.WriteString("=")
+		b.WriteString(labels[name])
+	}
+	return b.String()
+}
+
 // clear reset all the metrics
 func (e *Exporter) clear() {
 	// protect consistency
@@ -203,9 +526,85 @@ func (e *Exporter) clear() {
 	e.sensision.Reset()
 }
 
-// Scrape retrive HAProxy data
-func (e *Exporter) Scrape() bool {
-	body, err := e.fetch()
+// objTypeName maps the CSV/typed "type" field's numeric value to its
+// textual form. Outside 0-3 (e.g. a truly unknown future type) it's left
+// as-is.
+func objTypeName(v string) string {
+	switch v {
+	case "0":
+		return "frontend"
+	case "1":
+		return "backend"
+	case "2":
+		return "server"
+	case "3":
+		return "listen"
+	default:
+		return v
+	}
+}
+
+// normalizeEnumValue rewrites known enum-valued fields' textual HAProxy
+// states into the numeric form Prometheus/Sensision expect. It's called from
+// emitSeries so scrapeCSV, parseTyped and parseJSON all produce the same
+// value for a given field regardless of which runtime-API command parsed it.
+func normalizeEnumValue(field, value string) string {
+	switch field {
+	case "status":
+		switch value {
+		case "UP", "UP 1/3", "UP 2/3", "OPEN", "no check":
+			return "1"
+		case "DOWN", "DOWN 1/2", "NOLB", "MAINT":
+			return "0"
+		default:
+			return "0"
+		}
+	default:
+		return value
+	}
+}
+
+// emitSeries relabels one (field, value) sample for the given stats object
+// and, if it survives, appends it to both the Sensision buffer and e.rows.
+// scrapeCSV, parseTyped and parseJSON all funnel their samples through here
+// so the three runtime-API commands share one relabel/emit path.
+func (e *Exporter) emitSeries(now, pxname, svname, typ, field, value string) {
+	value = normalizeEnumValue(field, value)
+
+	seriesLabels := make(map[string]string, len(e.userLabels)+4)
+	for k, v := range e.userLabels {
+		seriesLabels[k] = v
+	}
+	seriesLabels["pxname"] = pxname
+	seriesLabels["svname"] = svname
+	seriesLabels["type"] = typ
+	seriesLabels["__name__"] = field
+
+	seriesLabels, keep := applyRelabel(e.relabel, seriesLabels)
+	if !keep {
+		return
+	}
+
+	name := seriesLabels["__name__"]
+	delete(seriesLabels, "__name__")
+
+	gts := now + name + "{" + formatSensisionLabels(seriesLabels) + "} " + value + "\n"
+	e.sensision.WriteString(gts)
+
+	e.rows = append(e.rows, statRow{name: name, labels: seriesLabels, value: value})
+}
+
+// Scrape retrive HAProxy data. Cancelling or expiring the supplied context
+// unblocks a fetchHTTP request, or a fetchUnix dial, immediately. The
+// context's deadline, if it has one, is armed as the exporter's scrape
+// deadline, which is what interrupts a stuck fetchUnix read; cancelling the
+// context does not interrupt a unix-socket read before that deadline.
+func (e *Exporter) Scrape(ctx context.Context) bool {
+	if d, ok := ctx.Deadline(); ok {
+		e.SetScrapeDeadline(d)
+	}
+
+	body, err := e.fetch(ctx)
 
 	// Delete previous metrics
 	e.clear()
@@ -221,6 +620,29 @@ func (e *Exporter) Scrape() bool {
 	e.mutex.Lock()
 	defer e.mutex.Unlock()
 
+	// A fresh slice, not e.rows[:0], so a Collect that already copied the
+	// old slice header under RLock keeps reading an immutable snapshot
+	// instead of racing this scrape's in-place appends.
+	e.rows = nil
+
+	switch e.command {
+	case "show stat typed":
+		err = e.parseTyped(body, now)
+	case "show stat json":
+		err = e.parseJSON(body, now)
+	default:
+		err = e.scrapeCSV(body, now)
+	}
+	if err != nil {
+		log.Errorf("%s: %v", e.URI, err)
+	}
+
+	return true
+}
+
+// scrapeCSV parses the legacy "show stat" CSV output, picking fields out of
+// each record by the fixed column indices in e.metrics.
+func (e *Exporter) scrapeCSV(body io.Reader, now string) error {
 	r := yacr.DefaultReader(body)
 	r.SkipRecords(1) // first line is comment
 
@@ -242,46 +664,164 @@ func (e *Exporter) Scrape() bool {
 
 		if r.EndOfRecord() {
 			i = 0
+
+			t := objTypeName(*values[32])
+			pxname, svname := *values[0], *values[1]
+
 			for fieldIdx := range e.metrics {
 				valueStr := values[fieldIdx]
 				if *valueStr == "" {
 					continue
 				}
 
-				value := *valueStr
-				if fieldIdx == 17 { // status field
-					switch *valueStr {
-					case "UP", "UP 1/3", "UP 2/3", "OPEN", "no check":
-						value = "1"
-					case "DOWN", "DOWN 1/2", "NOLB", "MAINT":
-						value = "0"
-					default:
-						value = "0"
-					}
-				}
-
-				t := ""
-				switch *values[32] {
-				case "0":
-					t = "frontend"
-				case "1":
-					t = "backend"
-				case "2":
-					t = "server"
-				case "3":
-					t = "listen"
-				}
-
-				gts := now + e.metrics[fieldIdx] + "{" + e.labels + "pxname=" + *values[0] + ",svname=" + *values[1] + ",type=" + t + "} " + value + "\n"
-				e.sensision.WriteString(gts)
+				e.emitSeries(now, pxname, svname, t, e.metrics[fieldIdx], *valueStr)
 			}
 		} else {
 			i++
 		}
 	}
-	if err := r.Err(); err != nil {
-		fmt.Println(err)
+
+	return r.Err()
+}
+
+// parseTyped ingests "show stat typed" output: one line per field, shaped
+// like "<tag>:<origin>:<type>:<value>", where <tag> is itself
+// "<objtype>.<objtypenum>.<procnum>.<id>.<field_name>.<field_id>" (e.g.
+// "F.2.0.0.pxname.1:MGP:str:stats"). There's no separate "type" field like
+// in the CSV output; the object type is the single-letter first component
+// of the tag (F/B/S/L). Each stats object's lines start with its "pxname"
+// field, so rather than assume a fixed column order (like scrapeCSV's
+// rowLength), fields are grouped by that boundary and flushed as a row once
+// the next "pxname" line starts a new one.
+func (e *Exporter) parseTyped(body io.Reader, now string) error {
+	scanner := bufio.NewScanner(body)
+
+	var pxname, svname, typ string
+	fields := make(map[string]string)
+	started := false
+
+	flush := func() {
+		for field, value := range fields {
+			e.emitSeries(now, pxname, svname, typ, field, value)
+		}
+		fields = make(map[string]string)
 	}
 
-	return true
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		parts := strings.SplitN(line, ":", 4)
+		if len(parts) != 4 {
+			continue
+		}
+		prefix, value := parts[0], parts[3]
+
+		dot := strings.Split(prefix, ".")
+		if len(dot) != 6 {
+			continue
+		}
+		objType, field := dot[0], dot[4]
+
+		if field == "pxname" {
+			if started {
+				flush()
+			}
+			started = true
+			pxname, svname = "", ""
+			typ = objTypeLetter(objType)
+		}
+
+		switch field {
+		case "pxname":
+			pxname = value
+		case "svname":
+			svname = value
+		default:
+			if e.trackedFields[field] {
+				fields[field] = value
+			}
+		}
+	}
+	if started {
+		flush()
+	}
+
+	return scanner.Err()
+}
+
+// objTypeLetter maps "show stat typed"'s single-letter object-type tag
+// (F/B/S/L) to the same textual form objTypeName produces for the CSV
+// "type" field's numeric value. An unrecognized letter is left as-is.
+func objTypeLetter(l string) string {
+	switch l {
+	case "F":
+		return "frontend"
+	case "B":
+		return "backend"
+	case "S":
+		return "server"
+	case "L":
+		return "listen"
+	default:
+		return l
+	}
+}
+
+// jsonStatValue is the typed value payload of one "show stat json" field.
+type jsonStatValue struct {
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+// jsonStatField is one element of a "show stat json" row: the row's object
+// type, which field it carries, and the field's typed value.
+type jsonStatField struct {
+	ObjType string `json:"objType"`
+	Field   struct {
+		Name string `json:"name"`
+	} `json:"field"`
+	Value jsonStatValue `json:"value"`
+}
+
+// parseJSON ingests HAProxy 2.x "show stat json" output: an array of rows,
+// each row an array of fields already carrying their own name/type/value, so
+// unlike scrapeCSV there's no fixed column layout to track.
+func (e *Exporter) parseJSON(body io.Reader, now string) error {
+	var rows [][]jsonStatField
+	if err := json.NewDecoder(body).Decode(&rows); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		if len(row) == 0 {
+			continue
+		}
+
+		var pxname, svname string
+		typ := strings.ToLower(row[0].ObjType)
+		fields := make(map[string]string, len(row))
+
+		for _, f := range row {
+			value := fmt.Sprintf("%v", f.Value.Value)
+			switch f.Field.Name {
+			case "pxname":
+				pxname = value
+			case "svname":
+				svname = value
+			default:
+				if e.trackedFields[f.Field.Name] {
+					fields[f.Field.Name] = value
+				}
+			}
+		}
+
+		for field, value := range fields {
+			e.emitSeries(now, pxname, svname, typ, field, value)
+		}
+	}
+
+	return nil
 }
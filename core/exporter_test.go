@@ -0,0 +1,51 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+// typedSample is a trimmed, real "show stat typed" capture: one frontend
+// row and one server row sharing a backend.
+const typedSample = `F.2.0.0.pxname.1:MGP:str:stats
+F.2.0.0.svname.2:MGP:str:FRONTEND
+F.2.0.0.scur.9:MGP:u32:3
+F.2.0.0.stot.10:MGP:u64:42
+S.2.0.1.pxname.1:MGP:str:stats
+S.2.0.1.svname.2:MGP:str:srv1
+S.2.0.1.scur.9:MGP:u32:1
+S.2.0.1.stot.10:MGP:u64:7
+`
+
+func TestParseTyped(t *testing.T) {
+	e, err := NewExporter(Config{URI: "unix:///tmp/haproxy.sock"})
+	if err != nil {
+		t.Fatalf("NewExporter: %v", err)
+	}
+
+	if err := e.parseTyped(strings.NewReader(typedSample), "now// "); err != nil {
+		t.Fatalf("parseTyped: %v", err)
+	}
+
+	if len(e.rows) == 0 {
+		t.Fatal("parseTyped produced zero metrics, want at least one per row")
+	}
+
+	want := map[string]string{
+		"frontend/FRONTEND/scur": "3",
+		"frontend/FRONTEND/stot": "42",
+		"server/srv1/scur":       "1",
+		"server/srv1/stot":       "7",
+	}
+	got := map[string]string{}
+	for _, row := range e.rows {
+		key := row.labels["type"] + "/" + row.labels["svname"] + "/" + row.name
+		got[key] = row.value
+	}
+
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("row %q = %q, want %q", key, got[key], value)
+		}
+	}
+}
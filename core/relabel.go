@@ -0,0 +1,134 @@
+package core
+
+import (
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// RelabelAction is the action a RelabelConfig rule performs, following
+// Prometheus's relabel_configs vocabulary.
+type RelabelAction string
+
+// Supported relabel actions.
+const (
+	RelabelKeep      RelabelAction = "keep"
+	RelabelDrop      RelabelAction = "drop"
+	RelabelReplace   RelabelAction = "replace"
+	RelabelLabelDrop RelabelAction = "labeldrop"
+	RelabelLabelKeep RelabelAction = "labelkeep"
+	RelabelHashMod   RelabelAction = "hashmod"
+)
+
+// RelabelConfig is one entry of a relabel_configs pipeline, applied to the
+// {pxname, svname, type, __name__, ...user labels} set of every emitted
+// series before it reaches Sensision or Prometheus.
+type RelabelConfig struct {
+	SourceLabels []string      `mapstructure:"source_labels"`
+	Separator    string        `mapstructure:"separator"`
+	Regex        string        `mapstructure:"regex"`
+	TargetLabel  string        `mapstructure:"target_label"`
+	Replacement  string        `mapstructure:"replacement"`
+	Modulus      uint64        `mapstructure:"modulus"`
+	Action       RelabelAction `mapstructure:"action"`
+
+	regex *regexp.Regexp
+}
+
+// compileRelabelConfigs fills in the vocabulary's defaults and pre-compiles
+// each rule's regex, so Scrape only ever matches against an already-compiled
+// *regexp.Regexp.
+func compileRelabelConfigs(cfgs []RelabelConfig) ([]RelabelConfig, error) {
+	compiled := make([]RelabelConfig, len(cfgs))
+	for i, c := range cfgs {
+		if c.Separator == "" {
+			c.Separator = ";"
+		}
+		if c.Regex == "" {
+			c.Regex = "(.*)"
+		}
+		if c.Action == "" {
+			c.Action = RelabelReplace
+		}
+		if c.Replacement == "" && c.Action == RelabelReplace {
+			c.Replacement = "$1"
+		}
+
+		re, err := regexp.Compile("^(?:" + c.Regex + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("relabel_configs[%d]: %v", i, err)
+		}
+		c.regex = re
+
+		compiled[i] = c
+	}
+	return compiled, nil
+}
+
+// applyRelabel runs set through the compiled relabel pipeline, in order.
+// It returns the resulting label set and whether the series survives: a
+// "keep" rule that fails to match, or a "drop" rule that matches, drops it.
+func applyRelabel(rules []RelabelConfig, set map[string]string) (map[string]string, bool) {
+	labels := make(map[string]string, len(set))
+	for k, v := range set {
+		labels[k] = v
+	}
+
+	for _, rule := range rules {
+		values := make([]string, len(rule.SourceLabels))
+		for i, name := range rule.SourceLabels {
+			values[i] = labels[name]
+		}
+		joined := strings.Join(values, rule.Separator)
+
+		switch rule.Action {
+		case RelabelKeep:
+			if !rule.regex.MatchString(joined) {
+				return nil, false
+			}
+
+		case RelabelDrop:
+			if rule.regex.MatchString(joined) {
+				return nil, false
+			}
+
+		case RelabelReplace:
+			match := rule.regex.FindStringSubmatchIndex(joined)
+			if match == nil {
+				continue
+			}
+			result := rule.regex.ExpandString(nil, rule.Replacement, joined, match)
+			if len(result) == 0 {
+				delete(labels, rule.TargetLabel)
+				continue
+			}
+			labels[rule.TargetLabel] = string(result)
+
+		case RelabelLabelDrop:
+			for name := range labels {
+				if rule.regex.MatchString(name) {
+					delete(labels, name)
+				}
+			}
+
+		case RelabelLabelKeep:
+			for name := range labels {
+				if !rule.regex.MatchString(name) {
+					delete(labels, name)
+				}
+			}
+
+		case RelabelHashMod:
+			if rule.Modulus == 0 {
+				continue
+			}
+			sum := fnv.New64a()
+			sum.Write([]byte(joined))
+			labels[rule.TargetLabel] = strconv.FormatUint(sum.Sum64()%rule.Modulus, 10)
+		}
+	}
+
+	return labels, true
+}